@@ -0,0 +1,214 @@
+package serveraclinit
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/hashicorp/consul-k8s/control-plane/subcommand/common"
+	"github.com/hashicorp/consul-k8s/control-plane/subcommand/flags"
+)
+
+// Command bootstraps ACLs on a Consul cluster running in Kubernetes and
+// distributes tokens to the other Consul-k8s components.
+type Command struct {
+	UI cli.Ui
+
+	flags       *flag.FlagSet
+	consulFlags *flags.ConsulFlags
+
+	flagK8sNamespace    string
+	flagResourcePrefix  string
+	flagSetServerTokens bool
+
+	// flagBootstrapTokenSecretName and flagBootstrapTokenSecretKey let an
+	// operator pre-seed the ACL bootstrap secret with a token they generated
+	// themselves instead of letting Consul mint a random one.
+	flagBootstrapTokenSecretName string
+	flagBootstrapTokenSecretKey  string
+
+	// flagRecoveryTokenFile, flagRecoveryTokenSecretName and
+	// flagRecoveryTokenSecretKey configure a pre-existing management-tier ACL
+	// token to recover a cluster whose bootstrap token was lost.
+	flagRecoveryTokenFile       string
+	flagRecoveryTokenSecretName string
+	flagRecoveryTokenSecretKey  string
+
+	clientset kubernetes.Interface
+
+	ctx           context.Context
+	once          sync.Once
+	help          string
+	log           hclog.Logger
+	retryInterval time.Duration
+}
+
+func (c *Command) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.flagK8sNamespace, "k8s-namespace", "",
+		"Name of the Kubernetes namespace in which Consul is deployed")
+	c.flags.StringVar(&c.flagResourcePrefix, "resource-prefix", "",
+		"Prefix used for Kubernetes resources created by the Helm chart, e.g. \"consul\"")
+	c.flags.BoolVar(&c.flagSetServerTokens, "set-server-tokens", true,
+		"Toggle for creating an ACL token for the servers")
+
+	c.flags.StringVar(&c.flagBootstrapTokenSecretName, "bootstrap-token-secret-name", "",
+		"Name of the Kubernetes secret that contains an operator-provided ACL bootstrap token. "+
+			"If set, this token is sent to Consul's ACL bootstrap API instead of letting Consul generate one.")
+	c.flags.StringVar(&c.flagBootstrapTokenSecretKey, "bootstrap-token-secret-key", "",
+		"Key in the Secret named by -bootstrap-token-secret-name that holds the bootstrap token. "+
+			"Defaults to the same key server-acl-init writes the generated bootstrap token under.")
+
+	c.flags.StringVar(&c.flagRecoveryTokenFile, "recovery-token-file", "",
+		"Path to a file containing an existing global-management ACL token to use for recovering "+
+			"a cluster whose bootstrap token was lost.")
+	c.flags.StringVar(&c.flagRecoveryTokenSecretName, "recovery-token-secret-name", "",
+		"Name of the Kubernetes secret that contains an existing global-management ACL token to use "+
+			"for recovering a cluster whose bootstrap token was lost. Ignored if -recovery-token-file is set.")
+	c.flags.StringVar(&c.flagRecoveryTokenSecretKey, "recovery-token-secret-key", "",
+		"Key in the Secret named by -recovery-token-secret-name that holds the recovery token. "+
+			"Defaults to the same key server-acl-init writes the generated bootstrap token under.")
+
+	c.consulFlags = &flags.ConsulFlags{}
+	flags.Merge(c.flags, c.consulFlags.Flags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *Command) Run(args []string) int {
+	var runErr error
+	c.once.Do(func() {
+		runErr = c.run(args)
+	})
+	if runErr != nil {
+		c.UI.Error(runErr.Error())
+		return 1
+	}
+	return 0
+}
+
+func (c *Command) run(args []string) error {
+	c.init()
+	if err := c.flags.Parse(args); err != nil {
+		return err
+	}
+
+	if c.ctx == nil {
+		c.ctx = context.Background()
+	}
+	if c.log == nil {
+		c.log = hclog.Default()
+	}
+	if c.retryInterval == 0 {
+		c.retryInterval = 1 * time.Second
+	}
+
+	if c.clientset == nil {
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return fmt.Errorf("constructing in-cluster Kubernetes config: %s", err)
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("creating Kubernetes client: %s", err)
+		}
+		c.clientset = clientset
+	}
+
+	serverAddresses, err := c.discoverServerAddresses()
+	if err != nil {
+		return err
+	}
+
+	bootTokenSecretName := fmt.Sprintf("%s-bootstrap-acl-token", c.flagResourcePrefix)
+	var bootstrapToken string
+	if secret, err := c.clientset.CoreV1().Secrets(c.flagK8sNamespace).Get(c.ctx, bootTokenSecretName, metav1.GetOptions{}); err == nil {
+		bootstrapToken = string(secret.Data[common.ACLTokenSecretKey])
+	}
+
+	_, err = c.bootstrapServers(serverAddresses, bootstrapToken, bootTokenSecretName)
+	return err
+}
+
+// discoverServerAddresses lists the Consul server pods for this release and
+// returns their IPs, retrying until at least one is found and all have been
+// assigned an IP.
+func (c *Command) discoverServerAddresses() ([]net.IPAddr, error) {
+	labelSelector := fmt.Sprintf("app=consul,component=server,release=%s", c.flagResourcePrefix)
+
+	var pods *apiv1.PodList
+	err := c.untilSucceeds("discovering Consul server pods", func() error {
+		var err error
+		pods, err = c.clientset.CoreV1().Pods(c.flagK8sNamespace).List(c.ctx, metav1.ListOptions{LabelSelector: labelSelector})
+		if err != nil {
+			return err
+		}
+		if len(pods.Items) == 0 {
+			return fmt.Errorf("no Consul server pods found matching %q", labelSelector)
+		}
+		for _, pod := range pods.Items {
+			if pod.Status.PodIP == "" {
+				return fmt.Errorf("pod %s has no IP address yet", pod.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]net.IPAddr, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(pod.Status.PodIP)})
+	}
+	return addrs, nil
+}
+
+// untilSucceeds runs op in a retry loop, logging each failure, until it
+// succeeds or the command's context is done.
+func (c *Command) untilSucceeds(opName string, op func() error) error {
+	for {
+		if err := c.ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", opName, err)
+		}
+
+		err := op()
+		if err == nil {
+			c.log.Debug(opName + ": success")
+			return nil
+		}
+		c.log.Error(opName+": failed, retrying", "err", err)
+
+		select {
+		case <-c.ctx.Done():
+			return fmt.Errorf("%s: %w", opName, c.ctx.Err())
+		case <-time.After(c.retryInterval):
+		}
+	}
+}
+
+func (c *Command) Synopsis() string {
+	return "Initialize ACLs on Consul servers and components"
+}
+
+func (c *Command) Help() string {
+	c.once.Do(c.init)
+	return c.help
+}
+
+const help = `
+Usage: consul-k8s-control-plane server-acl-init [options]
+
+  Bootstraps ACLs on Consul servers running in Kubernetes and distributes
+  tokens to the other Consul-k8s components.
+
+`
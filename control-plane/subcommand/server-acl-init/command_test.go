@@ -0,0 +1,59 @@
+package serveraclinit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestDiscoverServerAddresses verifies that Run()'s server-discovery step
+// actually finds the Consul server pods for the release, rather than the
+// command silently doing nothing.
+func TestDiscoverServerAddresses(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "consul-server-0",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "consul", "component": "server", "release": "consul"},
+		},
+		Status: apiv1.PodStatus{PodIP: "127.0.0.1"},
+	})
+
+	c := &Command{
+		flagK8sNamespace:   "default",
+		flagResourcePrefix: "consul",
+		clientset:          clientset,
+		ctx:                context.Background(),
+		log:                hclog.NewNullLogger(),
+	}
+
+	addrs, err := c.discoverServerAddresses()
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	require.Equal(t, "127.0.0.1", addrs[0].IP.String())
+}
+
+// TestDiscoverServerAddresses_NoPods verifies that discovery surfaces a clear
+// error instead of silently returning zero server addresses.
+func TestDiscoverServerAddresses_NoPods(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	c := &Command{
+		flagK8sNamespace:   "default",
+		flagResourcePrefix: "consul",
+		clientset:          fake.NewSimpleClientset(),
+		ctx:                ctx,
+		log:                hclog.NewNullLogger(),
+		retryInterval:      10 * time.Millisecond,
+	}
+
+	_, err := c.discoverServerAddresses()
+	require.Error(t, err)
+}
@@ -1,10 +1,15 @@
 package serveraclinit
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -16,6 +21,9 @@ import (
 	"github.com/hashicorp/consul-k8s/control-plane/subcommand/common"
 )
 
+// aclTokenUUIDRe matches the UUID format Consul requires for ACL token secret IDs.
+var aclTokenUUIDRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 // bootstrapServers bootstraps ACLs and ensures each server has an ACL token.
 // If bootstrapToken is not empty then ACLs are already bootstrapped.
 func (c *Command) bootstrapServers(serverAddresses []net.IPAddr, bootstrapToken, bootTokenSecretName string) (string, error) {
@@ -65,12 +73,29 @@ func (c *Command) bootstrapACLs(firstServerAddr, bootTokenSecretName string) (st
 		return "", fmt.Errorf("creating Consul client for address %s: %s", firstServerAddr, err)
 	}
 
+	operatorToken, err := c.operatorBootstrapToken()
+	if err != nil {
+		return "", err
+	}
+
 	// Call bootstrap ACLs API.
 	var bootstrapToken string
 	var unrecoverableErr error
+	useOperatorToken := operatorToken != ""
 	err = c.untilSucceeds("bootstrapping ACLs - PUT /v1/acl/bootstrap",
 		func() error {
-			bootstrapResp, _, err := consulClient.ACL().Bootstrap()
+			var bootstrapResp *api.ACLToken
+			var err error
+			if useOperatorToken {
+				bootstrapResp, err = bootstrapWithSecret(config.HttpClient, config.Scheme, firstServerAddr, operatorToken)
+				if err != nil && strings.Contains(err.Error(), "Unexpected response code: 400") {
+					c.log.Warn("Consul does not support operator-provided bootstrap tokens, falling back to a Consul-generated token", "err", err)
+					useOperatorToken = false
+					bootstrapResp, _, err = consulClient.ACL().Bootstrap()
+				}
+			} else {
+				bootstrapResp, _, err = consulClient.ACL().Bootstrap()
+			}
 			if err == nil {
 				bootstrapToken = bootstrapResp.SecretID
 				return nil
@@ -78,9 +103,34 @@ func (c *Command) bootstrapACLs(firstServerAddr, bootTokenSecretName string) (st
 
 			// Check if already bootstrapped.
 			if strings.Contains(err.Error(), "Unexpected response code: 403") {
+				// If we were given an operator-provided token, it may already have been
+				// used to bootstrap ACLs on a previous, partially-completed run. Rather
+				// than declaring the token lost, verify it still works before giving up.
+				if operatorToken != "" {
+					if verifyErr := c.verifyACLToken(firstServerAddr, operatorToken); verifyErr == nil {
+						c.log.Info("ACLs already bootstrapped - operator-provided bootstrap token is valid, using it")
+						bootstrapToken = operatorToken
+						return nil
+					}
+				}
+
+				// As a last resort, see if the operator has given us a pre-existing
+				// management token we can adopt as the bootstrap token.
+				recoveryToken, recoveryErr := c.recoveryManagementToken(firstServerAddr)
+				if recoveryErr != nil {
+					unrecoverableErr = fmt.Errorf("ACLs already bootstrapped but the ACL token was not written to a Kubernetes secret,"+
+						" and the provided recovery token could not be used: %s", recoveryErr)
+					return nil
+				}
+				if recoveryToken != "" {
+					c.log.Info("ACLs already bootstrapped - adopting the provided recovery token as the bootstrap token")
+					bootstrapToken = recoveryToken
+					return nil
+				}
+
 				unrecoverableErr = errors.New("ACLs already bootstrapped but the ACL token was not written to a Kubernetes secret." +
 					" We can't proceed because the bootstrap token is lost." +
-					" You must reset ACLs.")
+					" You must reset ACLs, or provide a recovery management token via -recovery-token-secret-name/-recovery-token-file.")
 				return nil
 			}
 
@@ -122,6 +172,116 @@ func (c *Command) bootstrapACLs(firstServerAddr, bootTokenSecretName string) (st
 	return bootstrapToken, err
 }
 
+// operatorBootstrapToken returns an operator-provided ACL token to use for
+// bootstrapping, read from the Kubernetes Secret configured via
+// -bootstrap-token-secret-name/-bootstrap-token-secret-key. It returns an
+// empty string if no such Secret was configured, so that bootstrapACLs falls
+// back to letting Consul mint a random token.
+func (c *Command) operatorBootstrapToken() (string, error) {
+	if c.flagBootstrapTokenSecretName == "" {
+		return "", nil
+	}
+
+	secretKey := c.flagBootstrapTokenSecretKey
+	if secretKey == "" {
+		secretKey = common.ACLTokenSecretKey
+	}
+
+	secret, err := c.clientset.CoreV1().Secrets(c.flagK8sNamespace).Get(c.ctx, c.flagBootstrapTokenSecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("reading bootstrap token Secret %q: %s", c.flagBootstrapTokenSecretName, err)
+	}
+
+	token := strings.TrimSpace(string(secret.Data[secretKey]))
+	if token == "" {
+		return "", fmt.Errorf("Secret %q does not contain key %q", c.flagBootstrapTokenSecretName, secretKey)
+	}
+	if !aclTokenUUIDRe.MatchString(token) {
+		return "", fmt.Errorf("value of key %q in Secret %q is not a valid ACL token UUID", secretKey, c.flagBootstrapTokenSecretName)
+	}
+	return token, nil
+}
+
+// verifyACLToken checks that token is a usable ACL token against the server
+// at serverAddr by making a read-only, privileged API call.
+func (c *Command) verifyACLToken(serverAddr, token string) error {
+	config := c.consulFlags.ConsulClientConfig().APIClientConfig
+	config.Address = serverAddr
+	config.Token = token
+	client, err := consul.NewClient(config, c.consulFlags.APITimeout)
+	if err != nil {
+		return err
+	}
+	_, _, err = client.ACL().TokenList(nil)
+	return err
+}
+
+// recoveryManagementToken returns an operator-supplied management-tier ACL
+// token to adopt as the bootstrap token when the original bootstrap token
+// has been lost, configured via -recovery-token-file or the Kubernetes
+// Secret referenced by -recovery-token-secret-name/-recovery-token-secret-key.
+// It returns an empty string and a nil error if no recovery token was
+// configured, and an error if one was configured but isn't usable.
+func (c *Command) recoveryManagementToken(serverAddr string) (string, error) {
+	token, err := c.readRecoveryToken()
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", nil
+	}
+	if !aclTokenUUIDRe.MatchString(token) {
+		return "", errors.New("recovery token is not a valid ACL token UUID")
+	}
+
+	config := c.consulFlags.ConsulClientConfig().APIClientConfig
+	config.Address = serverAddr
+	config.Token = token
+	client, err := consul.NewClient(config, c.consulFlags.APITimeout)
+	if err != nil {
+		return "", err
+	}
+
+	self, _, err := client.ACL().TokenReadSelf(nil)
+	if err != nil {
+		return "", fmt.Errorf("validating recovery token against /v1/acl/token/self: %s", err)
+	}
+	for _, policy := range self.Policies {
+		if policy.Name == "global-management" {
+			return token, nil
+		}
+	}
+	return "", errors.New("recovery token does not have global-management privileges")
+}
+
+// readRecoveryToken reads the raw recovery token from whichever source was
+// configured, preferring -recovery-token-file over the Kubernetes Secret
+// reference.
+func (c *Command) readRecoveryToken() (string, error) {
+	if c.flagRecoveryTokenFile != "" {
+		data, err := os.ReadFile(c.flagRecoveryTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("reading -recovery-token-file: %s", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if c.flagRecoveryTokenSecretName == "" {
+		return "", nil
+	}
+
+	secretKey := c.flagRecoveryTokenSecretKey
+	if secretKey == "" {
+		secretKey = common.ACLTokenSecretKey
+	}
+
+	secret, err := c.clientset.CoreV1().Secrets(c.flagK8sNamespace).Get(c.ctx, c.flagRecoveryTokenSecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("reading recovery token Secret %q: %s", c.flagRecoveryTokenSecretName, err)
+	}
+	return strings.TrimSpace(string(secret.Data[secretKey])), nil
+}
+
 // setServerTokens creates policies and associated ACL token for each server
 // and then provides the token to the server.
 func (c *Command) setServerTokens(serverAddresses []net.IPAddr, bootstrapToken string) error {
@@ -231,9 +391,79 @@ func (c *Command) setServerPolicy(consulClient *api.Client) (api.ACLPolicy, erro
 	return agentPolicy, nil
 }
 
+// agentRules returns the ACL rules applied to the Consul server agent token.
+func (c *Command) agentRules() (string, error) {
+	return `
+node_prefix "" {
+  policy = "write"
+}
+service_prefix "" {
+  policy = "read"
+}
+`, nil
+}
+
+// createOrUpdateACLPolicy creates policy in Consul, updating it in place if a
+// policy with the same name already exists.
+func (c *Command) createOrUpdateACLPolicy(policy api.ACLPolicy, consulClient *api.Client) error {
+	_, _, err := consulClient.ACL().PolicyCreate(&policy, nil)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(err.Error(), "Invalid Policy: A Policy with Name") {
+		return err
+	}
+
+	existing, _, err := consulClient.ACL().PolicyReadByName(policy.Name, nil)
+	if err != nil {
+		return err
+	}
+	policy.ID = existing.ID
+	_, _, err = consulClient.ACL().PolicyUpdate(&policy, nil)
+	return err
+}
+
 // isNoLeaderErr returns true if err is due to trying to call the
 // bootstrap ACLs API when there is no leader elected.
 func isNoLeaderErr(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "Unexpected response code: 500") &&
 		strings.Contains(err.Error(), "The ACL system is currently in legacy mode.")
 }
+
+// bootstrapWithSecret calls PUT /v1/acl/bootstrap with a BootstrapSecret body,
+// matching the Consul API on versions that accept an operator-provided
+// bootstrap token. The generated ACL().Bootstrap() client method takes no
+// arguments and can't express this field, so the request is made directly
+// with the standard library instead of relying on an unverified client method.
+func bootstrapWithSecret(httpClient *http.Client, scheme, addr, secret string) (*api.ACLToken, error) {
+	if scheme == "" {
+		scheme = "http"
+	}
+	body, err := json.Marshal(map[string]string{"BootstrapSecret": secret})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s://%s/v1/acl/bootstrap", scheme, addr), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected response code: %d (%s)", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var token api.ACLToken
+	if err := json.Unmarshal(respBody, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,226 @@
+package serveraclinit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/hashicorp/consul-k8s/control-plane/subcommand/common"
+	"github.com/hashicorp/consul-k8s/control-plane/subcommand/flags"
+)
+
+// enableACLs configures a Consul test server with the ACL system turned on.
+func enableACLs(c *testutil.TestServerConfig) {
+	c.ACL.Enabled = true
+}
+
+func testCommand(t *testing.T, srv *testutil.TestServer) *Command {
+	t.Helper()
+	return &Command{
+		flagK8sNamespace: "default",
+		consulFlags:      &flags.ConsulFlags{APITimeout: 5 * time.Second},
+		clientset:        fake.NewSimpleClientset(),
+		ctx:              context.Background(),
+		log:              hclog.NewNullLogger(),
+	}
+}
+
+// putSecret creates a Secret pre-seeded with token under key (or under
+// common.ACLTokenSecretKey if key is empty), simulating an operator-provided
+// token that's been written ahead of time.
+func putSecret(t *testing.T, c *Command, name, key, token string) {
+	t.Helper()
+	if key == "" {
+		key = common.ACLTokenSecretKey
+	}
+	_, err := c.clientset.CoreV1().Secrets(c.flagK8sNamespace).Create(c.ctx, &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data:       map[string][]byte{key: []byte(token)},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
+
+// TestBootstrapACLs_OperatorToken_HappyPath verifies that a valid
+// operator-provided token is sent as the bootstrap secret and accepted as
+// the cluster's bootstrap token.
+func TestBootstrapACLs_OperatorToken_HappyPath(t *testing.T) {
+	operatorToken := "f3f41279-6b94-4a6d-99b1-d5f9f6c4b8a1"
+
+	srv, err := testutil.NewTestServerConfigT(t, enableACLs)
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	c := testCommand(t, srv)
+	c.flagBootstrapTokenSecretName = "operator-token"
+	putSecret(t, c, "operator-token", "", operatorToken)
+
+	bootstrapToken, err := c.bootstrapACLs(srv.HTTPAddr, "consul-bootstrap-acl-token")
+	require.NoError(t, err)
+	require.Equal(t, operatorToken, bootstrapToken)
+}
+
+// TestBootstrapACLs_AlreadyBootstrapped_ValidOperatorToken simulates a
+// cluster that was already bootstrapped (e.g. by a previous, partially
+// completed run) using the pre-seeded operator token. server-acl-init should
+// verify the token works and use it instead of declaring it lost.
+func TestBootstrapACLs_AlreadyBootstrapped_ValidOperatorToken(t *testing.T) {
+	srv, err := testutil.NewTestServerConfigT(t, enableACLs)
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	client, err := api.NewClient(&api.Config{Address: srv.HTTPAddr})
+	require.NoError(t, err)
+	bootstrapResp, _, err := client.ACL().Bootstrap()
+	require.NoError(t, err)
+
+	c := testCommand(t, srv)
+	c.flagBootstrapTokenSecretName = "operator-token"
+	putSecret(t, c, "operator-token", "", bootstrapResp.SecretID)
+
+	bootstrapToken, err := c.bootstrapACLs(srv.HTTPAddr, "consul-bootstrap-acl-token")
+	require.NoError(t, err)
+	require.Equal(t, bootstrapResp.SecretID, bootstrapToken)
+}
+
+// TestBootstrapACLs_AlreadyBootstrapped_RecoveryToken simulates the token-lost
+// scenario: ACLs are bootstrapped but the Kubernetes secret holding the
+// bootstrap token never got created, and no operator token was pre-seeded.
+// An operator-supplied management token should be validated and adopted.
+func TestBootstrapACLs_AlreadyBootstrapped_RecoveryToken(t *testing.T) {
+	srv, err := testutil.NewTestServerConfigT(t, enableACLs)
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	client, err := api.NewClient(&api.Config{Address: srv.HTTPAddr})
+	require.NoError(t, err)
+	managementToken, _, err := client.ACL().Bootstrap()
+	require.NoError(t, err)
+
+	c := testCommand(t, srv)
+	c.flagRecoveryTokenSecretName = "recovery-token"
+	putSecret(t, c, "recovery-token", "", managementToken.SecretID)
+
+	bootstrapToken, err := c.bootstrapACLs(srv.HTTPAddr, "consul-bootstrap-acl-token")
+	require.NoError(t, err)
+	require.Equal(t, managementToken.SecretID, bootstrapToken)
+}
+
+// TestBootstrapACLs_AlreadyBootstrapped_RecoveryTokenWithoutGlobalManagement
+// verifies that a recovery token without global-management privileges is
+// rejected rather than silently adopted.
+func TestBootstrapACLs_AlreadyBootstrapped_RecoveryTokenWithoutGlobalManagement(t *testing.T) {
+	srv, err := testutil.NewTestServerConfigT(t, enableACLs)
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	client, err := api.NewClient(&api.Config{Address: srv.HTTPAddr})
+	require.NoError(t, err)
+	managementToken, _, err := client.ACL().Bootstrap()
+	require.NoError(t, err)
+
+	limitedToken, _, err := client.ACL().TokenCreate(&api.ACLToken{
+		Policies: []*api.ACLTokenPolicyLink{},
+	}, &api.WriteOptions{Token: managementToken.SecretID})
+	require.NoError(t, err)
+
+	c := testCommand(t, srv)
+	c.flagRecoveryTokenSecretName = "recovery-token"
+	putSecret(t, c, "recovery-token", "", limitedToken.SecretID)
+
+	_, err = c.bootstrapACLs(srv.HTTPAddr, "consul-bootstrap-acl-token")
+	require.Error(t, err)
+}
+
+// TestBootstrapACLs_AlreadyBootstrapped_NoRecoveryConfigured verifies the
+// existing unrecoverable error is preserved when no operator or recovery
+// token has been configured at all.
+func TestBootstrapACLs_AlreadyBootstrapped_NoRecoveryConfigured(t *testing.T) {
+	srv, err := testutil.NewTestServerConfigT(t, enableACLs)
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	client, err := api.NewClient(&api.Config{Address: srv.HTTPAddr})
+	require.NoError(t, err)
+	_, _, err = client.ACL().Bootstrap()
+	require.NoError(t, err)
+
+	c := testCommand(t, srv)
+
+	_, err = c.bootstrapACLs(srv.HTTPAddr, "consul-bootstrap-acl-token")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bootstrap token is lost")
+}
+
+// TestOperatorBootstrapToken_InvalidUUID verifies that a malformed token in
+// the pre-seeded Secret is rejected before ever calling the bootstrap API.
+func TestOperatorBootstrapToken_InvalidUUID(t *testing.T) {
+	c := &Command{
+		flagK8sNamespace:             "default",
+		flagBootstrapTokenSecretName: "operator-token",
+		clientset:                    fake.NewSimpleClientset(),
+		ctx:                          context.Background(),
+	}
+	putSecret(t, c, "operator-token", "", "not-a-uuid")
+
+	_, err := c.operatorBootstrapToken()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not a valid ACL token UUID")
+}
+
+// TestReadRecoveryToken_PreExistingSecretKey verifies that a recovery token
+// can be honored from a custom key in a pre-existing Secret.
+func TestReadRecoveryToken_PreExistingSecretKey(t *testing.T) {
+	c := &Command{
+		flagK8sNamespace:            "default",
+		flagRecoveryTokenSecretName: "recovery-token",
+		flagRecoveryTokenSecretKey:  "legacy-token",
+		clientset:                   fake.NewSimpleClientset(),
+		ctx:                         context.Background(),
+	}
+	putSecret(t, c, "recovery-token", "legacy-token", "f3f41279-6b94-4a6d-99b1-d5f9f6c4b8a1")
+
+	token, err := c.readRecoveryToken()
+	require.NoError(t, err)
+	require.Equal(t, "f3f41279-6b94-4a6d-99b1-d5f9f6c4b8a1", token)
+}
+
+// TestBootstrapServers_Idempotent verifies that once a bootstrap token has
+// been recovered and written to the Kubernetes secret, a subsequent run of
+// bootstrapServers short-circuits straight to using it.
+func TestBootstrapServers_Idempotent(t *testing.T) {
+	srv, err := testutil.NewTestServerConfigT(t, enableACLs)
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	client, err := api.NewClient(&api.Config{Address: srv.HTTPAddr})
+	require.NoError(t, err)
+	managementToken, _, err := client.ACL().Bootstrap()
+	require.NoError(t, err)
+
+	c := testCommand(t, srv)
+	c.flagRecoveryTokenSecretName = "recovery-token"
+	putSecret(t, c, "recovery-token", "", managementToken.SecretID)
+
+	bootstrapToken, err := c.bootstrapACLs(srv.HTTPAddr, "consul-bootstrap-acl-token")
+	require.NoError(t, err)
+	require.Equal(t, managementToken.SecretID, bootstrapToken)
+
+	// A subsequent run passes the token it already retrieved from the
+	// now-populated Secret, so it should not need to call bootstrapACLs again.
+	secret, err := c.clientset.CoreV1().Secrets(c.flagK8sNamespace).Get(c.ctx, "consul-bootstrap-acl-token", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, managementToken.SecretID, string(secret.Data[common.ACLTokenSecretKey]))
+
+	token, err := c.bootstrapServers([]net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, string(secret.Data[common.ACLTokenSecretKey]), "consul-bootstrap-acl-token")
+	require.NoError(t, err)
+	require.Equal(t, managementToken.SecretID, token)
+}